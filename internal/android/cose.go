@@ -0,0 +1,167 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/veraison/go-cose"
+)
+
+// PayloadFormat discriminates between the envelopes ValidateAttestation can
+// unwrap before checking app-identity and timestamp claims. Both formats
+// carry the same claims shape as the SafetyNet JWS.
+type PayloadFormat int
+
+const (
+	// PayloadFormatJWS is the SafetyNet JWS envelope.
+	PayloadFormatJWS PayloadFormat = iota
+	// PayloadFormatCOSE is a COSE_Sign1 envelope (RFC 8152), signed with
+	// ES256 or EdDSA and bound to the request via its external_aad.
+	PayloadFormatCOSE
+)
+
+// COSEKeyResolver resolves a COSE `kid` to its verification public key, for
+// issuers that reference a KMS-backed key rather than embedding an x5chain.
+type COSEKeyResolver interface {
+	PublicKey(ctx context.Context, kid []byte) (crypto.PublicKey, error)
+}
+
+var coseAllowedAlgorithms = map[cose.Algorithm]bool{
+	cose.AlgorithmES256: true,
+	cose.AlgorithmEdDSA: true,
+}
+
+// verifyCOSEAttestation verifies a base64-encoded COSE_Sign1 structure and
+// returns its payload decoded the same way as the SafetyNet JWS claims, so
+// callers can apply the same checks regardless of envelope. The signature
+// is verified with external_aad bound to opts.Nonce.
+func verifyCOSEAttestation(ctx context.Context, payload string, opts VerifyOpts) (map[string]interface{}, error) {
+	raw, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode COSE_Sign1 payload: %w", err)
+	}
+
+	msg := cose.NewSign1Message()
+	if err := msg.UnmarshalCBOR(raw); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal COSE_Sign1: %w", err)
+	}
+
+	alg, err := msg.Headers.Protected.Algorithm()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read COSE algorithm: %w", err)
+	}
+	if !coseAllowedAlgorithms[alg] {
+		return nil, fmt.Errorf("disallowed COSE algorithm %v", alg)
+	}
+
+	key, err := resolveCOSEKey(ctx, msg, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve COSE signer key: %w", err)
+	}
+
+	verifier, err := cose.NewVerifier(alg, key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build COSE verifier: %w", err)
+	}
+
+	externalAAD := []byte(opts.Nonce.Nonce())
+	if err := msg.Verify(externalAAD, verifier); err != nil {
+		return nil, fmt.Errorf("COSE_Sign1 signature verification failed: %w", err)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(msg.Payload, &claims); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal COSE payload: %w", err)
+	}
+	// The nonce binding was already enforced via external_aad above; inject
+	// it into claims so the remaining checks in ValidateAttestation, which
+	// decode claims["nonce"], apply uniformly across both envelopes.
+	claims["nonce"] = base64.StdEncoding.EncodeToString(externalAAD)
+
+	return claims, nil
+}
+
+func resolveCOSEKey(ctx context.Context, msg *cose.Sign1Message, opts VerifyOpts) (crypto.PublicKey, error) {
+	// Header label keys are cose.Algorithm-style int64 values once decoded
+	// (see cose.HeaderLabelX5Chain), not the default int type, so they must
+	// be looked up with the typed constant rather than an untyped literal.
+	if chain, ok := msg.Headers.Unprotected[cose.HeaderLabelX5Chain]; ok {
+		return resolveCOSEKeyFromChain(chain, opts.COSERoots)
+	}
+	if opts.COSEKeyResolver != nil {
+		kid := headerKeyID(msg.Headers.Protected)
+		if len(kid) == 0 {
+			kid = headerKeyID(msg.Headers.Unprotected)
+		}
+		if len(kid) == 0 {
+			return nil, fmt.Errorf("COSE_Sign1 has no kid to resolve")
+		}
+		return opts.COSEKeyResolver.PublicKey(ctx, kid)
+	}
+	return nil, fmt.Errorf("COSE_Sign1 has no x5chain and no COSEKeyResolver is configured")
+}
+
+func headerKeyID(h map[interface{}]interface{}) []byte {
+	kid, _ := h[cose.HeaderLabelKeyID].([]byte)
+	return kid
+}
+
+func resolveCOSEKeyFromChain(chain interface{}, roots *x509.CertPool) (crypto.PublicKey, error) {
+	var der [][]byte
+	switch v := chain.(type) {
+	case []byte:
+		der = [][]byte{v}
+	case [][]byte:
+		der = v
+	case []interface{}:
+		for _, item := range v {
+			b, ok := item.([]byte)
+			if !ok {
+				return nil, fmt.Errorf("unsupported x5chain element encoding %T", item)
+			}
+			der = append(der, b)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported x5chain encoding %T", chain)
+	}
+	if len(der) == 0 {
+		return nil, fmt.Errorf("empty x5chain")
+	}
+
+	certs := make([]*x509.Certificate, 0, len(der))
+	for _, d := range der {
+		cert, err := x509.ParseCertificate(d)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse x5chain certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates}); err != nil {
+		return nil, fmt.Errorf("unable to verify x5chain: %w", err)
+	}
+
+	return certs[0].PublicKey, nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/veraison/go-cose"
+)
+
+func TestResolveCOSEKeyFromChain_EmptyChain(t *testing.T) {
+	if _, err := resolveCOSEKeyFromChain([][]byte{}, nil); err == nil {
+		t.Error("expected error for empty x5chain, got nil")
+	}
+}
+
+func TestVerifyCOSEAttestation_MalformedPayload(t *testing.T) {
+	opts := VerifyOpts{Nonce: NewNonce(publish)}
+	if _, err := verifyCOSEAttestation(context.Background(), "not-base64!!", opts); err == nil {
+		t.Error("expected error for malformed payload, got nil")
+	}
+}
+
+type staticCOSEKeyResolver struct {
+	kid []byte
+	key crypto.PublicKey
+}
+
+func (r *staticCOSEKeyResolver) PublicKey(ctx context.Context, kid []byte) (crypto.PublicKey, error) {
+	if string(kid) != string(r.kid) {
+		return nil, fmt.Errorf("unknown kid %x", kid)
+	}
+	return r.key, nil
+}
+
+func TestVerifyCOSEAttestation(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	signer, err := cose.NewSigner(cose.AlgorithmES256, key)
+	if err != nil {
+		t.Fatalf("unable to create signer: %v", err)
+	}
+
+	nonce := NewNonce(publish)
+	claims := map[string]interface{}{
+		"nonce":          base64.StdEncoding.EncodeToString([]byte(nonce.Nonce())),
+		"apkPackageName": appPackage,
+		"timestampMs":    float64(1000),
+	}
+	body, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("unable to marshal claims: %v", err)
+	}
+
+	kid := []byte("test-key-1")
+	msg := cose.NewSign1Message()
+	msg.Headers.Protected[cose.HeaderLabelAlgorithm] = cose.AlgorithmES256
+	msg.Headers.Protected[cose.HeaderLabelKeyID] = kid
+	msg.Payload = body
+	if err := msg.Sign(rand.Reader, []byte(nonce.Nonce()), signer); err != nil {
+		t.Fatalf("unable to sign COSE_Sign1: %v", err)
+	}
+	raw, err := msg.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("unable to marshal COSE_Sign1: %v", err)
+	}
+
+	opts := VerifyOpts{
+		Nonce:           nonce,
+		COSEKeyResolver: &staticCOSEKeyResolver{kid: kid, key: &key.PublicKey},
+	}
+	got, err := verifyCOSEAttestation(context.Background(), base64.StdEncoding.EncodeToString(raw), opts)
+	if err != nil {
+		t.Fatalf("expected valid COSE_Sign1, got error: %v", err)
+	}
+	if got["apkPackageName"] != appPackage {
+		t.Errorf("apkPackageName = %v, want %v", got["apkPackageName"], appPackage)
+	}
+}
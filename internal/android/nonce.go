@@ -0,0 +1,64 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/exposure-notifications-server/internal/model"
+)
+
+// Nonce calculates the expected nonce that device attestations must bind to
+// for a given publish request.
+type Nonce interface {
+	Nonce() string
+}
+
+type publishNonce struct {
+	data *model.Publish
+}
+
+// NewNonce returns a Nonce that derives its value from the contents of the
+// given publish request, so that the attestation cannot be replayed against
+// a different set of exposure keys.
+func NewNonce(data *model.Publish) Nonce {
+	return &publishNonce{data: data}
+}
+
+func (n *publishNonce) Nonce() string {
+	regions := make([]string, len(n.data.Regions))
+	for i, r := range n.data.Regions {
+		regions[i] = strings.ToUpper(r)
+	}
+	sort.Strings(regions)
+
+	keys := make([]string, 0, len(n.data.Keys))
+	for _, k := range n.data.Keys {
+		keys = append(keys, fmt.Sprintf("%v.%v.%v", k.Key, k.IntervalNumber, k.IntervalCount))
+	}
+	sort.Strings(keys)
+
+	// base64(sha256(appPackageName|transmissionRisk|keys|regions|verificationAuthorityName)),
+	// with keys and regions sorted so that the nonce does not depend on the
+	// order the device reported them in.
+	cleartext := fmt.Sprintf("%v|%v|%v|%v|%v", n.data.AppPackageName, n.data.TransmissionRisk,
+		strings.Join(keys, ","), strings.Join(regions, ","), n.data.VerificationAuthorityName)
+	sum := sha256.Sum256([]byte(cleartext))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,149 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+)
+
+// playIntegrityClaims is the subset of the Play Integrity API's decoded
+// token payload that ValidateAttestation checks.
+// See https://developer.android.com/google/play/integrity/verdict.
+type playIntegrityClaims struct {
+	RequestDetails struct {
+		RequestPackageName string `json:"requestPackageName"`
+		Nonce              string `json:"nonce"`
+		TimestampMillis    string `json:"timestampMillis"`
+	} `json:"requestDetails"`
+	AppIntegrity struct {
+		PackageName             string   `json:"packageName"`
+		CertificateSha256Digest []string `json:"certificateSha256Digest"`
+	} `json:"appIntegrity"`
+	DeviceIntegrity struct {
+		DeviceRecognitionVerdict []string `json:"deviceRecognitionVerdict"`
+	} `json:"deviceIntegrity"`
+}
+
+// validatePlayIntegrity verifies a Play Integrity API token and checks its
+// claims against opts. payload is either a JWE (decrypted with
+// opts.PlayIntegrityDecryptionKey) or, if that key is unset, the
+// Google-signed JWS variant.
+func validatePlayIntegrity(ctx context.Context, payload string, opts VerifyOpts) error {
+	signedJWS := payload
+	if len(opts.PlayIntegrityDecryptionKey) > 0 {
+		decrypted, err := decryptPlayIntegrityJWE(payload, opts.PlayIntegrityDecryptionKey)
+		if err != nil {
+			return fmt.Errorf("unable to decrypt Play Integrity token: %w", err)
+		}
+		signedJWS = decrypted
+	}
+
+	claims, err := verifyPlayIntegrityJWS(signedJWS, opts.PlayIntegrityVerificationKey)
+	if err != nil {
+		return fmt.Errorf("unable to verify Play Integrity token: %w", err)
+	}
+
+	nonceBytes, err := base64.StdEncoding.DecodeString(claims.RequestDetails.Nonce)
+	if err != nil {
+		return fmt.Errorf("unable to decode nonce: %w", err)
+	}
+	if string(nonceBytes) != opts.Nonce.Nonce() {
+		return fmt.Errorf("nonce mismatch")
+	}
+	if opts.AppPkgName != "" {
+		if claims.RequestDetails.RequestPackageName != opts.AppPkgName {
+			return fmt.Errorf("requestPackageName mismatch")
+		}
+		if claims.AppIntegrity.PackageName != opts.AppPkgName {
+			return fmt.Errorf("appIntegrity package name mismatch")
+		}
+	}
+	if opts.APKDigest != "" && !contains(claims.AppIntegrity.CertificateSha256Digest, opts.APKDigest) {
+		return fmt.Errorf("appIntegrity certificate digest mismatch")
+	}
+	for _, want := range opts.RequiredDeviceVerdicts {
+		if !contains(claims.DeviceIntegrity.DeviceRecognitionVerdict, want) {
+			return fmt.Errorf("missing required device verdict %q", want)
+		}
+	}
+
+	timestampMs, err := parseTimestampMillis(claims.RequestDetails.TimestampMillis)
+	if err != nil {
+		return fmt.Errorf("invalid timestampMillis: %w", err)
+	}
+	generated := time.Unix(timestampMs/1000, 0)
+	if generated.Before(opts.MinValidTime) {
+		return fmt.Errorf("attestation is too old, must be newer than %v, was %v", opts.MinValidTime.Unix(), generated.Unix())
+	}
+	if generated.After(opts.MaxValidTime) {
+		return fmt.Errorf("attestation is in the future, must be older than %v, was %v", opts.MaxValidTime.Unix(), generated.Unix())
+	}
+
+	return nil
+}
+
+// decryptPlayIntegrityJWE decrypts a compact-serialized JWE using key,
+// returning the nested JWS.
+func decryptPlayIntegrityJWE(payload string, key []byte) (string, error) {
+	jwe, err := jose.ParseEncrypted(payload)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse JWE: %w", err)
+	}
+	plaintext, err := jwe.Decrypt(key)
+	if err != nil {
+		return "", fmt.Errorf("unable to decrypt JWE: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// verifyPlayIntegrityJWS verifies a compact-serialized JWS against key and
+// decodes its payload into playIntegrityClaims.
+func verifyPlayIntegrityJWS(payload string, key interface{}) (*playIntegrityClaims, error) {
+	jws, err := jose.ParseSigned(payload)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse JWS: %w", err)
+	}
+	body, err := jws.Verify(key)
+	if err != nil {
+		return nil, fmt.Errorf("signature verification failed: %w", err)
+	}
+	var claims playIntegrityClaims
+	if err := json.Unmarshal(body, &claims); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal claims: %w", err)
+	}
+	return &claims, nil
+}
+
+// parseTimestampMillis parses the Play Integrity API's string-encoded
+// milliseconds-since-epoch timestamp.
+func parseTimestampMillis(s string) (int64, error) {
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func contains(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+
+	"github.com/google/exposure-notifications-server/internal/model"
+)
+
+func signPlayIntegrityClaims(t *testing.T, key *ecdsa.PrivateKey, claims playIntegrityClaims) string {
+	t.Helper()
+
+	body, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("unable to marshal claims: %v", err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, nil)
+	if err != nil {
+		t.Fatalf("unable to create signer: %v", err)
+	}
+	jws, err := signer.Sign(body)
+	if err != nil {
+		t.Fatalf("unable to sign claims: %v", err)
+	}
+	serialized, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatalf("unable to serialize JWS: %v", err)
+	}
+	return serialized
+}
+
+func TestValidatePlayIntegrity(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+
+	pub := &model.Publish{AppPackageName: appPackage, Regions: []string{"US"}}
+	nonce := NewNonce(pub)
+	now := time.Now()
+
+	var claims playIntegrityClaims
+	claims.RequestDetails.RequestPackageName = appPackage
+	claims.RequestDetails.Nonce = base64.StdEncoding.EncodeToString([]byte(nonce.Nonce()))
+	claims.RequestDetails.TimestampMillis = strconv.FormatInt(now.Unix()*1000, 10)
+	claims.AppIntegrity.PackageName = appPackage
+	claims.AppIntegrity.CertificateSha256Digest = []string{"deadbeef"}
+	claims.DeviceIntegrity.DeviceRecognitionVerdict = []string{"MEETS_DEVICE_INTEGRITY"}
+
+	token := signPlayIntegrityClaims(t, key, claims)
+
+	opts := VerifyOpts{
+		TokenType:                    TokenTypePlayIntegrity,
+		AppPkgName:                   appPackage,
+		APKDigest:                    "deadbeef",
+		Nonce:                        nonce,
+		MinValidTime:                 now.Add(-time.Minute),
+		MaxValidTime:                 now.Add(time.Minute),
+		PlayIntegrityVerificationKey: &key.PublicKey,
+		RequiredDeviceVerdicts:       []string{"MEETS_DEVICE_INTEGRITY"},
+	}
+	if err := ValidateAttestation(context.Background(), token, opts); err != nil {
+		t.Fatalf("expected valid token, got error: %v", err)
+	}
+
+	opts.RequiredDeviceVerdicts = []string{"MEETS_STRONG_INTEGRITY"}
+	if err := ValidateAttestation(context.Background(), token, opts); err == nil {
+		t.Fatal("expected error for missing device verdict, got nil")
+	}
+}
+
+func TestValidatePlayIntegrity_JWE(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+
+	pub := &model.Publish{AppPackageName: appPackage, Regions: []string{"US"}}
+	nonce := NewNonce(pub)
+	now := time.Now()
+
+	var claims playIntegrityClaims
+	claims.RequestDetails.RequestPackageName = appPackage
+	claims.RequestDetails.Nonce = base64.StdEncoding.EncodeToString([]byte(nonce.Nonce()))
+	claims.RequestDetails.TimestampMillis = strconv.FormatInt(now.Unix()*1000, 10)
+	claims.AppIntegrity.PackageName = appPackage
+	claims.DeviceIntegrity.DeviceRecognitionVerdict = []string{"MEETS_DEVICE_INTEGRITY"}
+
+	jws := signPlayIntegrityClaims(t, key, claims)
+
+	decryptionKey := make([]byte, 32)
+	if _, err := rand.Read(decryptionKey); err != nil {
+		t.Fatalf("unable to generate decryption key: %v", err)
+	}
+	encrypter, err := jose.NewEncrypter(jose.A256GCM, jose.Recipient{Algorithm: jose.DIRECT, Key: decryptionKey}, nil)
+	if err != nil {
+		t.Fatalf("unable to create encrypter: %v", err)
+	}
+	jwe, err := encrypter.Encrypt([]byte(jws))
+	if err != nil {
+		t.Fatalf("unable to encrypt JWE: %v", err)
+	}
+	token, err := jwe.CompactSerialize()
+	if err != nil {
+		t.Fatalf("unable to serialize JWE: %v", err)
+	}
+
+	opts := VerifyOpts{
+		TokenType:                    TokenTypePlayIntegrity,
+		AppPkgName:                   appPackage,
+		Nonce:                        nonce,
+		MinValidTime:                 now.Add(-time.Minute),
+		MaxValidTime:                 now.Add(time.Minute),
+		PlayIntegrityDecryptionKey:   decryptionKey,
+		PlayIntegrityVerificationKey: &key.PublicKey,
+		RequiredDeviceVerdicts:       []string{"MEETS_DEVICE_INTEGRITY"},
+	}
+	if err := ValidateAttestation(context.Background(), token, opts); err != nil {
+		t.Fatalf("expected valid token, got error: %v", err)
+	}
+
+	wrongKey := make([]byte, 32)
+	if _, err := rand.Read(wrongKey); err != nil {
+		t.Fatalf("unable to generate wrong key: %v", err)
+	}
+	opts.PlayIntegrityDecryptionKey = wrongKey
+	if err := ValidateAttestation(context.Background(), token, opts); err == nil {
+		t.Fatal("expected error decrypting with the wrong key, got nil")
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package android implements device attestation for Android devices, using
+// the deprecated SafetyNet Attestation API or its replacement, the Play
+// Integrity API.
+package android
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TokenType discriminates between the token formats ValidateAttestation
+// accepts. SafetyNet Attestation is deprecated in favor of the Play
+// Integrity API; TokenType lets publish handlers accept both during the
+// migration window.
+type TokenType int
+
+const (
+	// TokenTypeSafetyNet is the legacy SafetyNet Attestation JWS.
+	TokenTypeSafetyNet TokenType = iota
+	// TokenTypePlayIntegrity is a Play Integrity API token.
+	TokenTypePlayIntegrity
+)
+
+// VerifyOpts carries the per-app configuration and bounds that a SafetyNet
+// or Play Integrity attestation is checked against.
+type VerifyOpts struct {
+	// TokenType selects which verifier ValidateAttestation runs. It defaults
+	// to TokenTypeSafetyNet for backwards compatibility.
+	TokenType TokenType
+	// AppPkgName is the expected `apkPackageName` (SafetyNet) or
+	// `requestPackageName` / `appIntegrity.packageName` (Play Integrity).
+	AppPkgName string
+	// APKDigest is the expected `apkCertificateDigestSha256` (SafetyNet) or
+	// `appIntegrity.certificateSha256Digest` (Play Integrity), if enforced.
+	APKDigest string
+	// Nonce is the expected nonce, bound to the contents of the publish request.
+	Nonce Nonce
+	// CTSProfileMatch, if true, requires the attestation's ctsProfileMatch claim to be true.
+	CTSProfileMatch bool
+	// BasicIntegrity, if true, requires the attestation's basicIntegrity claim to be true.
+	BasicIntegrity bool
+	// MinValidTime and MaxValidTime bound the attestation's generation timestamp.
+	MinValidTime time.Time
+	MaxValidTime time.Time
+
+	// PlayIntegrityDecryptionKey, if set, is the AES key used to decrypt a
+	// Play Integrity token delivered as a JWE. If unset, the token is
+	// treated as the Google-signed (unencrypted) variant.
+	PlayIntegrityDecryptionKey []byte
+	// PlayIntegrityVerificationKey verifies the signature of the nested
+	// Play Integrity JWS, for either token variant.
+	PlayIntegrityVerificationKey *ecdsa.PublicKey
+	// RequiredDeviceVerdicts lists labels that must all be present in
+	// `deviceIntegrity.deviceRecognitionVerdict`, e.g. "MEETS_DEVICE_INTEGRITY".
+	RequiredDeviceVerdicts []string
+
+	// PayloadFormat selects the envelope ValidateAttestation unwraps before
+	// the app-identity and timestamp checks below are applied. It is
+	// ignored when TokenType is TokenTypePlayIntegrity. Defaults to
+	// PayloadFormatJWS.
+	PayloadFormat PayloadFormat
+	// COSERoots is the trusted CA bundle a PayloadFormatCOSE envelope's
+	// x5chain must verify against.
+	COSERoots *x509.CertPool
+	// COSEKeyResolver resolves a PayloadFormatCOSE envelope's kid when it
+	// carries no x5chain.
+	COSEKeyResolver COSEKeyResolver
+}
+
+// ValidateAttestation verifies a SafetyNet or Play Integrity attestation
+// token, per opts.TokenType, and checks its claims against opts.
+func ValidateAttestation(ctx context.Context, payload string, opts VerifyOpts) error {
+	if opts.Nonce == nil || opts.Nonce.Nonce() == "" {
+		return fmt.Errorf("missing nonce")
+	}
+	if opts.MinValidTime.IsZero() || opts.MaxValidTime.IsZero() {
+		return fmt.Errorf("missing timestamp bounds for attestation")
+	}
+
+	if opts.TokenType == TokenTypePlayIntegrity {
+		return validatePlayIntegrity(ctx, payload, opts)
+	}
+
+	var claims map[string]interface{}
+	var err error
+	if opts.PayloadFormat == PayloadFormatCOSE {
+		claims, err = verifyCOSEAttestation(ctx, payload, opts)
+	} else {
+		claims, err = verifyAttestation(ctx, payload)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to verify attestation: %w", err)
+	}
+
+	nonceBytes, err := base64.StdEncoding.DecodeString(asString(claims["nonce"]))
+	if err != nil {
+		return fmt.Errorf("unable to decode nonce: %w", err)
+	}
+	if string(nonceBytes) != opts.Nonce.Nonce() {
+		return fmt.Errorf("nonce mismatch")
+	}
+
+	if opts.AppPkgName != "" && asString(claims["apkPackageName"]) != opts.AppPkgName {
+		return fmt.Errorf("package name mismatch")
+	}
+	if opts.APKDigest != "" && !containsDigest(claims["apkCertificateDigestSha256"], opts.APKDigest) {
+		return fmt.Errorf("apk certificate digest mismatch")
+	}
+	if opts.CTSProfileMatch && !asBool(claims["ctsProfileMatch"]) {
+		return fmt.Errorf("ctsProfileMatch failed")
+	}
+	if opts.BasicIntegrity && !asBool(claims["basicIntegrity"]) {
+		return fmt.Errorf("basicIntegrity failed")
+	}
+
+	timestampMs, ok := claims["timestampMs"].(float64)
+	if !ok {
+		return fmt.Errorf("missing timestampMs in attestation")
+	}
+	generated := time.Unix(int64(timestampMs)/1000, 0)
+	if generated.Before(opts.MinValidTime) {
+		return fmt.Errorf("attestation is too old, must be newer than %v, was %v", opts.MinValidTime.Unix(), generated.Unix())
+	}
+	if generated.After(opts.MaxValidTime) {
+		return fmt.Errorf("attestation is in the future, must be older than %v, was %v", opts.MaxValidTime.Unix(), generated.Unix())
+	}
+
+	return nil
+}
+
+// verifyAttestation parses a SafetyNet JWS, verifies its signature against
+// the leaf certificate embedded in the `x5c` header, and returns the decoded
+// claims.
+func verifyAttestation(ctx context.Context, payload string) (map[string]interface{}, error) {
+	parts := strings.Split(payload, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed JWS, expected 3 segments, got %d", len(parts))
+	}
+
+	header, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode header: %w", err)
+	}
+	var jwsHeader struct {
+		Alg string   `json:"alg"`
+		X5c []string `json:"x5c"`
+	}
+	if err := json.Unmarshal(header, &jwsHeader); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal header: %w", err)
+	}
+	if len(jwsHeader.X5c) == 0 {
+		return nil, fmt.Errorf("missing x5c certificate chain")
+	}
+
+	leafDER, err := base64.StdEncoding.DecodeString(jwsHeader.X5c[0])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode leaf certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse leaf certificate: %w", err)
+	}
+	if err := leaf.VerifyHostname("attest.android.com"); err != nil {
+		return nil, fmt.Errorf("leaf certificate not issued for attest.android.com: %w", err)
+	}
+
+	pubKey, ok := leaf.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported leaf certificate public key type %T", leaf.PublicKey)
+	}
+
+	signed := parts[0] + "." + parts[1]
+	sig, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode signature: %w", err)
+	}
+	digest := sha256.Sum256([]byte(signed))
+	if err := rsa.VerifyPKCS1v15(pubKey, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, fmt.Errorf("signature verification failed: %w", err)
+	}
+
+	body, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode claims: %w", err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(body, &claims); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal claims: %w", err)
+	}
+	return claims, nil
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	if l := len(s) % 4; l > 0 {
+		s += strings.Repeat("=", 4-l)
+	}
+	return base64.URLEncoding.DecodeString(s)
+}
+
+func asString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func asBool(v interface{}) bool {
+	b, _ := v.(bool)
+	return b
+}
+
+func containsDigest(v interface{}, want string) bool {
+	digests, ok := v.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, d := range digests {
+		if asString(d) == want {
+			return true
+		}
+	}
+	return false
+}
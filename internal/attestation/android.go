@@ -0,0 +1,91 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attestation
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/google/exposure-notifications-server/internal/android"
+	"github.com/google/exposure-notifications-server/internal/model"
+)
+
+// AndroidAppConfig is the per-app SafetyNet / Play Integrity configuration
+// that publish handlers previously passed directly into android.VerifyOpts.
+type AndroidAppConfig struct {
+	// TokenType selects which Android attestation format this app submits.
+	// It defaults to android.TokenTypeSafetyNet for backwards compatibility
+	// with apps that have not yet migrated to Play Integrity.
+	TokenType android.TokenType
+
+	AppPkgName      string
+	APKDigest       string
+	CTSProfileMatch bool
+	BasicIntegrity  bool
+	// AttestationAge bounds how far the attestation's generation timestamp
+	// may drift from now in either direction.
+	AttestationAge time.Duration
+
+	// PlayIntegrityDecryptionKey and PlayIntegrityVerificationKey configure
+	// TokenTypePlayIntegrity verification; see android.VerifyOpts.
+	PlayIntegrityDecryptionKey   []byte
+	PlayIntegrityVerificationKey *ecdsa.PublicKey
+	RequiredDeviceVerdicts       []string
+
+	// PayloadFormat, COSERoots and COSEKeyResolver configure apps that
+	// submit their attestation as a COSE_Sign1 envelope instead of a JWS;
+	// see android.VerifyOpts. Ignored when TokenType is TokenTypePlayIntegrity.
+	PayloadFormat   android.PayloadFormat
+	COSERoots       *x509.CertPool
+	COSEKeyResolver android.COSEKeyResolver
+}
+
+type androidAttestor struct {
+	cfg AndroidAppConfig
+}
+
+// NewAndroidAttestor returns an Attestor that verifies SafetyNet or Play
+// Integrity attestations, per cfg.TokenType, using the existing
+// internal/android verifier.
+func NewAndroidAttestor(cfg AndroidAppConfig) Attestor {
+	return &androidAttestor{cfg: cfg}
+}
+
+func (a *androidAttestor) VerifyPublish(ctx context.Context, pub *model.Publish, opaquePayload []byte) error {
+	now := time.Now()
+	opts := android.VerifyOpts{
+		TokenType:                    a.cfg.TokenType,
+		AppPkgName:                   a.cfg.AppPkgName,
+		APKDigest:                    a.cfg.APKDigest,
+		Nonce:                        android.NewNonce(pub),
+		CTSProfileMatch:              a.cfg.CTSProfileMatch,
+		BasicIntegrity:               a.cfg.BasicIntegrity,
+		MinValidTime:                 now.Add(-a.cfg.AttestationAge),
+		MaxValidTime:                 now.Add(a.cfg.AttestationAge),
+		PlayIntegrityDecryptionKey:   a.cfg.PlayIntegrityDecryptionKey,
+		PlayIntegrityVerificationKey: a.cfg.PlayIntegrityVerificationKey,
+		RequiredDeviceVerdicts:       a.cfg.RequiredDeviceVerdicts,
+		PayloadFormat:                a.cfg.PayloadFormat,
+		COSERoots:                    a.cfg.COSERoots,
+		COSEKeyResolver:              a.cfg.COSEKeyResolver,
+	}
+	if err := android.ValidateAttestation(ctx, string(opaquePayload), opts); err != nil {
+		return fmt.Errorf("android: %w", err)
+	}
+	return nil
+}
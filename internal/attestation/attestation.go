@@ -0,0 +1,77 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package attestation defines a platform-agnostic device attestation
+// interface, so that publish handlers do not need to know the details of
+// any single platform's attestation format. Concrete verifiers live in
+// internal/android and internal/ios; this package only wires the chosen
+// verifier to a model.Publish's Platform field.
+package attestation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/exposure-notifications-server/internal/model"
+)
+
+// Attestor verifies that an opaque device attestation payload legitimately
+// accompanies the given publish request.
+type Attestor interface {
+	// VerifyPublish verifies opaquePayload against pub, returning a non-nil
+	// error if the attestation is missing, malformed, or does not bind to
+	// pub (for example because its nonce does not match).
+	VerifyPublish(ctx context.Context, pub *model.Publish, opaquePayload []byte) error
+}
+
+// Registry dispatches a publish request's attestation to the Attestor
+// registered for its Platform.
+type Registry struct {
+	attestors map[model.Platform]Attestor
+}
+
+// NewRegistry returns a Registry with no attestors configured; callers
+// register one per supported model.Platform with Register.
+func NewRegistry() *Registry {
+	return &Registry{attestors: map[model.Platform]Attestor{}}
+}
+
+// Register associates an Attestor with a platform. It panics if called
+// twice for the same platform, since that indicates a configuration bug.
+func (r *Registry) Register(platform model.Platform, a Attestor) {
+	if _, ok := r.attestors[platform]; ok {
+		panic(fmt.Sprintf("attestation: Attestor already registered for platform %q", platform))
+	}
+	r.attestors[platform] = a
+}
+
+// For returns the Attestor registered for platform, or an error if none is
+// configured.
+func (r *Registry) For(platform model.Platform) (Attestor, error) {
+	a, ok := r.attestors[platform]
+	if !ok {
+		return nil, fmt.Errorf("attestation: no Attestor registered for platform %q", platform)
+	}
+	return a, nil
+}
+
+// VerifyPublish looks up the Attestor for pub.Platform and verifies
+// opaquePayload against it.
+func (r *Registry) VerifyPublish(ctx context.Context, pub *model.Publish, opaquePayload []byte) error {
+	a, err := r.For(pub.Platform)
+	if err != nil {
+		return err
+	}
+	return a.VerifyPublish(ctx, pub, opaquePayload)
+}
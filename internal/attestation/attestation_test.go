@@ -0,0 +1,65 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attestation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/exposure-notifications-server/internal/model"
+)
+
+type stubAttestor struct {
+	called bool
+	err    error
+}
+
+func (s *stubAttestor) VerifyPublish(ctx context.Context, pub *model.Publish, opaquePayload []byte) error {
+	s.called = true
+	return s.err
+}
+
+func TestRegistry_VerifyPublish(t *testing.T) {
+	r := NewRegistry()
+	android := &stubAttestor{}
+	r.Register(model.PlatformAndroid, android)
+
+	if err := r.VerifyPublish(context.Background(), &model.Publish{Platform: model.PlatformAndroid}, nil); err != nil {
+		t.Fatalf("VerifyPublish: %v", err)
+	}
+	if !android.called {
+		t.Errorf("expected android attestor to be called")
+	}
+}
+
+func TestRegistry_VerifyPublish_UnknownPlatform(t *testing.T) {
+	r := NewRegistry()
+	err := r.VerifyPublish(context.Background(), &model.Publish{Platform: model.PlatformIOS}, nil)
+	if err == nil {
+		t.Fatal("expected error for unregistered platform, got nil")
+	}
+}
+
+func TestRegistry_Register_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic registering a platform twice")
+		}
+	}()
+
+	r := NewRegistry()
+	r.Register(model.PlatformAndroid, &stubAttestor{})
+	r.Register(model.PlatformAndroid, &stubAttestor{})
+}
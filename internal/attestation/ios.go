@@ -0,0 +1,75 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attestation
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/exposure-notifications-server/internal/android"
+	"github.com/google/exposure-notifications-server/internal/ios"
+	"github.com/google/exposure-notifications-server/internal/model"
+)
+
+// IOSAppConfig is the per-app App Attest configuration: the app's bundle
+// and team identifiers, the App Attest environments it is allowed to
+// attest from, and the CA bundle its attestation chain must verify against.
+type IOSAppConfig struct {
+	BundleID       string
+	TeamID         string
+	AllowedAAGUIDs []string
+	Roots          *x509.CertPool
+}
+
+// iosAttestationPayload is the opaque payload format devices submit: the
+// App Attest key identifier and the CBOR attestation object, as produced by
+// DCAppAttestService.attestKey(_:clientDataHash:).
+type iosAttestationPayload struct {
+	KeyID             []byte `json:"keyId"`
+	AttestationObject []byte `json:"attestationObject"`
+}
+
+type iosAttestor struct {
+	cfg IOSAppConfig
+}
+
+// NewIOSAttestor returns an Attestor that verifies Apple App Attest
+// attestation objects using the internal/ios verifier.
+func NewIOSAttestor(cfg IOSAppConfig) Attestor {
+	return &iosAttestor{cfg: cfg}
+}
+
+func (a *iosAttestor) VerifyPublish(ctx context.Context, pub *model.Publish, opaquePayload []byte) error {
+	var payload iosAttestationPayload
+	if err := json.Unmarshal(opaquePayload, &payload); err != nil {
+		return fmt.Errorf("app attest: unable to decode attestation payload: %w", err)
+	}
+
+	clientDataHash := sha256.Sum256([]byte(android.NewNonce(pub).Nonce()))
+	opts := ios.VerifyOpts{
+		BundleID:       a.cfg.BundleID,
+		TeamID:         a.cfg.TeamID,
+		AllowedAAGUIDs: a.cfg.AllowedAAGUIDs,
+		Roots:          a.cfg.Roots,
+		ClientDataHash: clientDataHash[:],
+	}
+	if err := ios.ValidateAttestation(ctx, payload.KeyID, payload.AttestationObject, opts); err != nil {
+		return fmt.Errorf("app attest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ios implements device attestation for iOS devices using Apple's
+// App Attest / DeviceCheck service.
+package ios
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// appAttestASN1OID is the extension OID (1.2.840.113635.100.8.2) Apple
+// embeds in the leaf certificate of an App Attest statement, carrying a
+// SHA-256 hash of authData || clientDataHash wrapped in an OCTET STRING.
+var appAttestASN1OID = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 8, 2}
+
+// VerifyOpts carries the per-app configuration that an App Attest
+// attestation object is checked against.
+type VerifyOpts struct {
+	// BundleID is the expected app bundle identifier.
+	BundleID string
+	// TeamID is the expected Apple developer team identifier.
+	TeamID string
+	// AllowedAAGUIDs restricts accepted attestations to specific App Attest
+	// environments (e.g. "appattest" for production, "appattestdevelop" for
+	// development builds).
+	AllowedAAGUIDs []string
+	// Roots is the set of trusted CAs that the attestation's x5c chain must
+	// chain up to; in practice this is Apple's App Attest Root CA.
+	Roots *x509.CertPool
+	// ClientDataHash binds the attestation to a specific publish request. It
+	// is the SHA-256 of android.NewNonce(publish).Nonce(), so a replayed
+	// attestation from a different publish request is rejected.
+	ClientDataHash []byte
+}
+
+type attestationObject struct {
+	Fmt      string          `cbor:"fmt"`
+	AttStmt  attestationStmt `cbor:"attStmt"`
+	AuthData []byte          `cbor:"authData"`
+}
+
+type attestationStmt struct {
+	X5c     [][]byte `cbor:"x5c"`
+	Receipt []byte   `cbor:"receipt"`
+}
+
+// authenticatorData is the fixed-layout prefix of the App Attest authData,
+// as defined by the WebAuthn authenticator data format Apple reuses.
+type authenticatorData struct {
+	RPIDHash  [32]byte
+	Flags     byte
+	Counter   uint32
+	AAGUID    [16]byte
+	CredIDLen uint16
+	CredID    []byte
+}
+
+// ValidateAttestation verifies an App Attest attestation object against
+// opts, including the Apple x5c chain, the RP-ID hash binding to the app's
+// bundle ID, and the nonce binding opts.ClientDataHash to this request.
+func ValidateAttestation(ctx context.Context, keyID []byte, attestationObj []byte, opts VerifyOpts) error {
+	if len(opts.ClientDataHash) == 0 {
+		return fmt.Errorf("missing client data hash for attestation")
+	}
+
+	var obj attestationObject
+	if err := cbor.Unmarshal(attestationObj, &obj); err != nil {
+		return fmt.Errorf("unable to decode attestation object: %w", err)
+	}
+	if obj.Fmt != "apple-appattest" {
+		return fmt.Errorf("unexpected attestation format %q", obj.Fmt)
+	}
+	if len(obj.AttStmt.X5c) == 0 {
+		return fmt.Errorf("missing x5c certificate chain")
+	}
+
+	authData, err := parseAuthenticatorData(obj.AuthData)
+	if err != nil {
+		return fmt.Errorf("unable to parse authData: %w", err)
+	}
+
+	expectedRPIDHash := sha256.Sum256([]byte(opts.TeamID + "." + opts.BundleID))
+	if !bytes.Equal(authData.RPIDHash[:], expectedRPIDHash[:]) {
+		return fmt.Errorf("rpIdHash mismatch for bundle %q", opts.BundleID)
+	}
+	if !bytes.Equal(authData.CredID, keyID) {
+		return fmt.Errorf("credential id does not match attested key id")
+	}
+	if len(opts.AllowedAAGUIDs) > 0 && !aaguidAllowed(authData.AAGUID, opts.AllowedAAGUIDs) {
+		return fmt.Errorf("aaguid %x is not an allowed App Attest environment", authData.AAGUID)
+	}
+
+	leaf, err := verifyX5cChain(obj.AttStmt.X5c, opts.Roots)
+	if err != nil {
+		return fmt.Errorf("unable to verify attestation certificate chain: %w", err)
+	}
+
+	nonce := sha256.Sum256(append(append([]byte{}, obj.AuthData...), opts.ClientDataHash...))
+	if err := verifyNonceExtension(leaf, nonce[:]); err != nil {
+		return fmt.Errorf("attestation nonce mismatch: %w", err)
+	}
+
+	return nil
+}
+
+func parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	const fixedLen = 32 + 1 + 4 + 16 + 2
+	if len(raw) < fixedLen {
+		return nil, fmt.Errorf("authData too short: %d bytes", len(raw))
+	}
+	ad := &authenticatorData{}
+	copy(ad.RPIDHash[:], raw[0:32])
+	ad.Flags = raw[32]
+	ad.Counter = uint32(raw[33])<<24 | uint32(raw[34])<<16 | uint32(raw[35])<<8 | uint32(raw[36])
+	copy(ad.AAGUID[:], raw[37:53])
+	ad.CredIDLen = uint16(raw[53])<<8 | uint16(raw[54])
+	if len(raw) < fixedLen+int(ad.CredIDLen) {
+		return nil, fmt.Errorf("authData too short for credential id of length %d", ad.CredIDLen)
+	}
+	ad.CredID = raw[fixedLen : fixedLen+int(ad.CredIDLen)]
+	return ad, nil
+}
+
+func aaguidAllowed(aaguid [16]byte, allowed []string) bool {
+	for _, a := range allowed {
+		var padded [16]byte
+		copy(padded[:], a)
+		if bytes.Equal(aaguid[:], padded[:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func verifyX5cChain(x5c [][]byte, roots *x509.CertPool) (*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, 0, len(x5c))
+	for _, der := range x5c {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+	}); err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
+
+func verifyNonceExtension(leaf *x509.Certificate, expectedNonce []byte) error {
+	for _, ext := range leaf.Extensions {
+		if !ext.Id.Equal(appAttestASN1OID) {
+			continue
+		}
+		var wrapped struct {
+			Nonce []byte `asn1:"tag:1,explicit"`
+		}
+		if _, err := asn1.Unmarshal(ext.Value, &wrapped); err != nil {
+			return fmt.Errorf("unable to decode nonce extension: %w", err)
+		}
+		if !bytes.Equal(wrapped.Nonce, expectedNonce) {
+			return fmt.Errorf("nonce does not match")
+		}
+		return nil
+	}
+	return fmt.Errorf("attestation certificate is missing the App Attest nonce extension")
+}
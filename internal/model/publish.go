@@ -0,0 +1,51 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package model defines the data model for publish requests accepted from
+// devices and the exposure keys derived from them.
+package model
+
+// ExposureKey is a single TemporaryExposureKey as reported by a device.
+type ExposureKey struct {
+	Key              string `json:"key"`
+	IntervalNumber   int32  `json:"intervalNumber"`
+	IntervalCount    int32  `json:"intervalCount"`
+	TransmissionRisk int    `json:"transmissionRisk,omitempty"`
+}
+
+// Publish represents the body of a publish request sent by a device after
+// a user has been diagnosed and chooses to share their keys.
+type Publish struct {
+	Keys                      []ExposureKey `json:"temporaryExposureKeys"`
+	Regions                   []string      `json:"regions"`
+	AppPackageName            string        `json:"appPackageName"`
+	TransmissionRisk          int           `json:"transmissionRisk"`
+	VerificationAuthorityName string        `json:"verificationAuthorityName"`
+	// Platform identifies which device attestation subsystem produced the
+	// opaque attestation payload accompanying this publish request.
+	Platform Platform `json:"platform"`
+}
+
+// Platform identifies the originating mobile OS of a publish request, and
+// therefore which Attestor must be used to verify it.
+type Platform string
+
+const (
+	// PlatformAndroid indicates the request was attested with SafetyNet (or
+	// its successor, the Play Integrity API).
+	PlatformAndroid Platform = "android"
+	// PlatformIOS indicates the request was attested with Apple's App
+	// Attest / DeviceCheck service.
+	PlatformIOS Platform = "ios"
+)
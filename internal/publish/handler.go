@@ -0,0 +1,48 @@
+// Copyright 2020 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package publish validates incoming publish requests, including their
+// device attestation, before the keys they carry are persisted.
+package publish
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/exposure-notifications-server/internal/attestation"
+	"github.com/google/exposure-notifications-server/internal/model"
+)
+
+// Handler validates publish requests.
+type Handler struct {
+	attestors *attestation.Registry
+}
+
+// NewHandler returns a Handler that dispatches attestation verification to
+// attestors based on each publish request's Platform.
+func NewHandler(attestors *attestation.Registry) *Handler {
+	return &Handler{attestors: attestors}
+}
+
+// ValidateAttestation verifies that opaquePayload is a valid device
+// attestation for pub, using the Attestor registered for pub.Platform.
+func (h *Handler) ValidateAttestation(ctx context.Context, pub *model.Publish, opaquePayload []byte) error {
+	if pub.Platform == "" {
+		return fmt.Errorf("publish: missing platform")
+	}
+	if err := h.attestors.VerifyPublish(ctx, pub, opaquePayload); err != nil {
+		return fmt.Errorf("publish: attestation failed: %w", err)
+	}
+	return nil
+}